@@ -0,0 +1,111 @@
+package https
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// basicAuthenticator enforces HTTP Basic Auth against a fixed set of
+// bcrypt-hashed passwords. bcrypt is deliberately slow, so successful
+// (username, password) pairs are cached keyed by a random per-process salt,
+// to avoid re-paying its cost on every scrape in a burst. Only the cache of a
+// basicAuthenticator changes after it is built; reloading the config builds
+// a fresh one and the old cache is dropped with it.
+type basicAuthenticator struct {
+	users map[string]string // username -> bcrypt hash
+	salt  []byte
+
+	mtx   sync.Mutex
+	cache map[string][sha256.Size]byte
+}
+
+func newBasicAuthenticator(users map[string]string) (*basicAuthenticator, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate basic auth cache salt: %s", err)
+	}
+	return &basicAuthenticator{
+		users: users,
+		salt:  salt,
+		cache: make(map[string][sha256.Size]byte),
+	}, nil
+}
+
+// dummyHash is compared against when username is unknown, so that
+// authenticate always pays for exactly one bcrypt comparison on a miss,
+// whether or not the username exists. Without it, an unknown username
+// would return immediately while a known one with a wrong password would
+// wait for bcrypt, letting an attacker enumerate valid usernames by
+// response time.
+var dummyHash = func() []byte {
+	h, err := bcrypt.GenerateFromPassword([]byte("https-basic-auth-dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err) // only fails for an out-of-range cost, and DefaultCost never is.
+	}
+	return h
+}()
+
+// authenticate reports whether username/password match a configured user.
+// The username comparison uses subtle.ConstantTimeCompare against every
+// configured user, and a failed match always costs one bcrypt comparison
+// (real or dummy), so that the response time does not reveal which
+// usernames are valid.
+func (b *basicAuthenticator) authenticate(username, password string) bool {
+	hash, known := dummyHash, false
+	for u, h := range b.users {
+		if subtle.ConstantTimeCompare([]byte(u), []byte(username)) == 1 {
+			hash, known = []byte(h), true
+		}
+	}
+
+	if known {
+		digest := b.digest(username, password)
+		b.mtx.Lock()
+		cached, ok := b.cache[username]
+		b.mtx.Unlock()
+		if ok && subtle.ConstantTimeCompare(cached[:], digest[:]) == 1 {
+			return true
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || !known {
+		return false
+	}
+
+	digest := b.digest(username, password)
+	b.mtx.Lock()
+	b.cache[username] = digest
+	b.mtx.Unlock()
+	return true
+}
+
+func (b *basicAuthenticator) digest(username, password string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(b.salt)
+	h.Write([]byte(username))
+	h.Write([]byte(password))
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func (b *basicAuthenticator) wrap(next http.Handler) http.Handler {
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || !b.authenticate(username, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="blackbox_exporter"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package https
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// systemCertPoolValue is the magic value accepted for the rootCAs/clientCAs
+// YAML fields that requests the host's trust store instead of (or as a
+// base for) a PEM file.
+const systemCertPoolValue = "system"
+
+// buildCertPool builds the trust store for RootCAs/ClientCAs: starting from
+// the OS trust store when useSystem is set, with pemPath's certificates
+// appended on top, if given. This lets operators probe internal services
+// whose certificate chain ends in a public root without vendoring the
+// entire Mozilla CA bundle.
+//
+// x509.SystemCertPool historically failed on Windows; rather than treat
+// that as fatal, we log a warning and fall back to an empty pool so
+// operators relying only on pemPath aren't affected.
+func buildCertPool(useSystem bool, pemPath string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if useSystem {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			level.Warn(logger).Log("msg", "system cert pool is unavailable, falling back to an empty pool", "err", err)
+		} else {
+			pool = systemPool
+		}
+	}
+	if pemPath != "" {
+		pem, err := ioutil.ReadFile(pemPath)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", pemPath)
+		}
+	}
+	return pool, nil
+}
+
+// certPoolRequest resolves whether to start from the system trust store and
+// which PEM file, if any, to layer on top, from a rootCAs/clientCAs YAML
+// value plus its companion useSystemRoots/useSystemClientCAs boolean. The
+// sentinel value "system" is equivalent to setting the boolean with no PEM
+// file.
+func certPoolRequest(useSystem bool, pathOrSentinel string) (bool, string) {
+	if pathOrSentinel == systemCertPoolValue {
+		return true, ""
+	}
+	return useSystem, pathOrSentinel
+}
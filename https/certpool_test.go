@@ -0,0 +1,112 @@
+package https
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertPEM writes a minimal self-signed certificate to path, for
+// tests that only need something x509.AppendCertsFromPEM will accept.
+func writeTestCertPEM(t *testing.T, path string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "https package test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %s", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(path, pemBytes, 0o644); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+}
+
+func TestCertPoolRequest(t *testing.T) {
+	cases := []struct {
+		name           string
+		useSystem      bool
+		pathOrSentinel string
+		wantUseSystem  bool
+		wantPath       string
+	}{
+		{"sentinel implies system with no PEM file", false, systemCertPoolValue, true, ""},
+		{"boolean plus explicit path layers both", true, "/some/path.pem", true, "/some/path.pem"},
+		{"neither set is a plain empty pool", false, "", false, ""},
+		{"path alone is unaffected", false, "/some/path.pem", false, "/some/path.pem"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gotUseSystem, gotPath := certPoolRequest(c.useSystem, c.pathOrSentinel)
+			if gotUseSystem != c.wantUseSystem || gotPath != c.wantPath {
+				t.Errorf("certPoolRequest(%v, %q) = (%v, %q), want (%v, %q)",
+					c.useSystem, c.pathOrSentinel, gotUseSystem, gotPath, c.wantUseSystem, c.wantPath)
+			}
+		})
+	}
+}
+
+func TestBuildCertPool(t *testing.T) {
+	t.Run("PEM file is appended", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.pem")
+		writeTestCertPEM(t, path)
+
+		pool, err := buildCertPool(false, path)
+		if err != nil {
+			t.Fatalf("buildCertPool: %s", err)
+		}
+		if len(pool.Subjects()) != 1 { //nolint:staticcheck // Subjects is deprecated but fine for this assertion.
+			t.Errorf("expected exactly one certificate in the pool, got %d", len(pool.Subjects())) //nolint:staticcheck
+		}
+	})
+
+	t.Run("missing PEM file is an error", func(t *testing.T) {
+		if _, err := buildCertPool(false, filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+			t.Fatal("expected an error for a nonexistent PEM file")
+		}
+	})
+
+	t.Run("PEM file with no certificates is an error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "empty.pem")
+		if err := os.WriteFile(path, []byte("not a certificate"), 0o644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		if _, err := buildCertPool(false, path); err == nil {
+			t.Fatal("expected an error for a PEM file with no certificates")
+		}
+	})
+
+	t.Run("useSystem alone never errors, even if the system pool is unavailable", func(t *testing.T) {
+		if _, err := buildCertPool(true, ""); err != nil {
+			t.Fatalf("buildCertPool(true, \"\") = %s, want no error (fall back to an empty pool instead)", err)
+		}
+	})
+
+	t.Run("useSystem plus a PEM file layers the PEM certs on top", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.pem")
+		writeTestCertPEM(t, path)
+
+		pool, err := buildCertPool(true, path)
+		if err != nil {
+			t.Fatalf("buildCertPool: %s", err)
+		}
+		if len(pool.Subjects()) == 0 { //nolint:staticcheck
+			t.Error("expected the appended PEM certificate to be present in the pool")
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package https
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// logger is used for the package's own operational logging (failed
+// reloads, unavailable system cert pools, config watcher errors, ...). It
+// defaults to discarding everything so embedders who don't call SetLogger
+// see no output, matching the behavior before this package logged anything.
+var logger log.Logger = log.NewNopLogger()
+
+// SetLogger sets the logger used for this package's operational logging.
+func SetLogger(l log.Logger) {
+	logger = l
+}
+
+// Handshake results recorded in handshakesTotal. Most of crypto/tls's own
+// rejections (an unverified client cert under Require*/Verify* ClientAuth,
+// or a ClientHello offering no version/cipher we allow) happen before any
+// tls.Config callback runs and so can't be observed directly; "version_
+// mismatch" and "cipher_mismatch" are therefore predicted ahead of time
+// from the ClientHello instead. "bad_cert" is the exception: it is only
+// ever recorded for ClientAuth: RequestClientCert, the one mode where
+// crypto/tls hands us an unverified certificate and expects our own check.
+const (
+	resultOK              = "ok"
+	resultBadCert         = "bad_cert"
+	resultVersionMismatch = "version_mismatch"
+	resultCipherMismatch  = "cipher_mismatch"
+)
+
+var (
+	handshakesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blackbox_exporter_tls_server_handshakes_total",
+		Help: "Total number of TLS handshakes against the exporter's own HTTPS listener, by result.",
+	}, []string{"result"})
+
+	handshakeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "blackbox_exporter_tls_server_handshake_duration_seconds",
+		Help:    "Duration of TLS handshakes against the exporter's own HTTPS listener.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	certExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "blackbox_exporter_tls_server_cert_expiry_seconds",
+		Help: "notAfter of the leaf certificate currently served by the exporter's own HTTPS listener, as Unix seconds. Alert on (blackbox_exporter_tls_server_cert_expiry_seconds - time()) < 7 * 24 * 3600.",
+	})
+)
+
+// Register registers this package's TLS handshake metrics with r. Listen
+// does not do this on its own, so a binary embedding it (such as the
+// blackbox_exporter main) opts in explicitly and controls which registry
+// they land in.
+func Register(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{handshakesTotal, handshakeDuration, certExpiry} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// predictHandshakeMismatch inspects hello against cfg's negotiable TLS
+// versions and cipher suites, and reports the result label to count, if
+// any, before the handshake actually proceeds. CipherSuites only
+// constrains TLS 1.2 and below, so it is not considered once the
+// negotiated version would be TLS 1.3.
+func predictHandshakeMismatch(cfg *tls.Config, hello *tls.ClientHelloInfo) (string, bool) {
+	negotiated, ok := negotiatedVersion(cfg, hello.SupportedVersions)
+	if !ok {
+		return resultVersionMismatch, true
+	}
+	if negotiated < tls.VersionTLS13 && len(cfg.CipherSuites) > 0 && !ciphersOverlap(cfg.CipherSuites, hello.CipherSuites) {
+		return resultCipherMismatch, true
+	}
+	return "", false
+}
+
+// negotiatedVersion reports the highest TLS version both cfg and offered
+// agree on, the same way crypto/tls itself would pick it.
+func negotiatedVersion(cfg *tls.Config, offered []uint16) (uint16, bool) {
+	// cfg.MinVersion is never 0 here: loadTLSConfig always defaults it to
+	// TLS 1.2 before a *tls.Config reaches this function. Leaving it at 0
+	// rather than special-casing it to some floor is harmless either way,
+	// since every real version constant is > 0.
+	min, max := cfg.MinVersion, cfg.MaxVersion
+	if max == 0 {
+		max = tls.VersionTLS13
+	}
+	if len(offered) == 0 {
+		// No supported_versions extension: a legacy client. Let crypto/tls
+		// decide; we have no version to weigh the cipher suites against.
+		return max, true
+	}
+	var best uint16
+	found := false
+	for _, v := range offered {
+		if v >= min && v <= max && v > best {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+func ciphersOverlap(configured, offered []uint16) bool {
+	allowed := make(map[uint16]bool, len(configured))
+	for _, c := range configured {
+		allowed[c] = true
+	}
+	for _, o := range offered {
+		if allowed[o] {
+			return true
+		}
+	}
+	return false
+}
+
+// withHandshakeMetrics clones cfg and adds a VerifyConnection hook that
+// counts completed handshakes by result and times them, and a
+// GetCertificate hook that keeps certExpiry up to date, without disturbing
+// any GetCertificate cfg already has.
+func withHandshakeMetrics(cfg *tls.Config) *tls.Config {
+	out := cfg.Clone()
+
+	if getCertificate := out.GetCertificate; getCertificate != nil {
+		out.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, err := getCertificate(hello)
+			if err == nil && cert != nil {
+				observeCertExpiry(cert)
+			}
+			return cert, err
+		}
+	}
+
+	start := time.Now()
+	out.VerifyConnection = func(cs tls.ConnectionState) error {
+		// RequestClientCert asks for a client certificate but, unlike the
+		// Require*/Verify* modes, does not itself verify it against
+		// ClientCAs or check its validity period. Here only the leaf's
+		// validity period is checked, and a failure is counted as bad_cert;
+		// the certificate is not verified against ClientCAs.
+		if out.ClientAuth == tls.RequestClientCert && len(cs.PeerCertificates) > 0 {
+			leaf := cs.PeerCertificates[0]
+			now := time.Now()
+			if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+				handshakesTotal.WithLabelValues(resultBadCert).Inc()
+				return fmt.Errorf("https: client certificate is not valid at this time")
+			}
+		}
+		handshakesTotal.WithLabelValues(resultOK).Inc()
+		handshakeDuration.Observe(time.Since(start).Seconds())
+		return nil
+	}
+
+	return out
+}
+
+// observeCertExpiry parses cert's leaf, if not already parsed, and sets
+// certExpiry from its notAfter.
+func observeCertExpiry(cert *tls.Certificate) {
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+			cert.Leaf = leaf
+		}
+	}
+	if cert.Leaf != nil {
+		certExpiry.Set(float64(cert.Leaf.NotAfter.Unix()))
+	}
+}
@@ -0,0 +1,116 @@
+package https
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestNegotiatedVersion(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     *tls.Config
+		offered []uint16
+		want    uint16
+		wantOK  bool
+	}{
+		{
+			name:    "picks the highest mutually acceptable version",
+			cfg:     &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS13},
+			offered: []uint16{tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13},
+			want:    tls.VersionTLS13,
+			wantOK:  true,
+		},
+		{
+			name:    "client offers nothing at or above the configured floor",
+			cfg:     &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS13},
+			offered: []uint16{tls.VersionTLS10, tls.VersionTLS11},
+			want:    0,
+			wantOK:  false,
+		},
+		{
+			name:    "MaxVersion of 0 is treated as TLS 1.3",
+			cfg:     &tls.Config{MinVersion: tls.VersionTLS12},
+			offered: []uint16{tls.VersionTLS12, tls.VersionTLS13},
+			want:    tls.VersionTLS13,
+			wantOK:  true,
+		},
+		{
+			name:    "no supported_versions extension defers to crypto/tls",
+			cfg:     &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS13},
+			offered: nil,
+			want:    tls.VersionTLS13,
+			wantOK:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := negotiatedVersion(c.cfg, c.offered)
+			if got != c.want || ok != c.wantOK {
+				t.Errorf("negotiatedVersion(%+v, %v) = (%d, %v), want (%d, %v)", c.cfg, c.offered, got, ok, c.want, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestCiphersOverlap(t *testing.T) {
+	configured := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_256_CBC_SHA}
+
+	if !ciphersOverlap(configured, []uint16{tls.TLS_RSA_WITH_AES_256_CBC_SHA, tls.TLS_RSA_WITH_AES_128_CBC_SHA}) {
+		t.Error("expected an overlap when one offered cipher is configured")
+	}
+	if ciphersOverlap(configured, []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}) {
+		t.Error("expected no overlap when no offered cipher is configured")
+	}
+	if ciphersOverlap(configured, nil) {
+		t.Error("expected no overlap against an empty offered list")
+	}
+}
+
+func TestPredictHandshakeMismatch(t *testing.T) {
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		MaxVersion:   tls.VersionTLS13,
+		CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_256_CBC_SHA},
+	}
+
+	t.Run("version mismatch", func(t *testing.T) {
+		hello := &tls.ClientHelloInfo{SupportedVersions: []uint16{tls.VersionTLS10, tls.VersionTLS11}}
+		result, bad := predictHandshakeMismatch(cfg, hello)
+		if !bad || result != resultVersionMismatch {
+			t.Errorf("predictHandshakeMismatch = (%q, %v), want (%q, true)", result, bad, resultVersionMismatch)
+		}
+	})
+
+	t.Run("cipher mismatch below TLS 1.3", func(t *testing.T) {
+		hello := &tls.ClientHelloInfo{
+			SupportedVersions: []uint16{tls.VersionTLS12},
+			CipherSuites:      []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA},
+		}
+		result, bad := predictHandshakeMismatch(cfg, hello)
+		if !bad || result != resultCipherMismatch {
+			t.Errorf("predictHandshakeMismatch = (%q, %v), want (%q, true)", result, bad, resultCipherMismatch)
+		}
+	})
+
+	t.Run("TLS 1.3 ignores CipherSuites entirely", func(t *testing.T) {
+		hello := &tls.ClientHelloInfo{
+			SupportedVersions: []uint16{tls.VersionTLS13},
+			CipherSuites:      []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}, // not in cfg.CipherSuites
+		}
+		result, bad := predictHandshakeMismatch(cfg, hello)
+		if bad {
+			t.Errorf("predictHandshakeMismatch = (%q, %v), want no mismatch for a TLS 1.3 handshake", result, bad)
+		}
+	})
+
+	t.Run("no mismatch", func(t *testing.T) {
+		hello := &tls.ClientHelloInfo{
+			SupportedVersions: []uint16{tls.VersionTLS12},
+			CipherSuites:      []uint16{tls.TLS_RSA_WITH_AES_256_CBC_SHA},
+		}
+		result, bad := predictHandshakeMismatch(cfg, hello)
+		if bad {
+			t.Errorf("predictHandshakeMismatch = (%q, %v), want no mismatch", result, bad)
+		}
+	})
+}
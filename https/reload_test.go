@@ -0,0 +1,130 @@
+package https
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// authenticates drives a request through state.handler, the same locked path
+// production traffic takes, rather than reading state.auth directly - which
+// would race with the background reload goroutine started by watchConfig.
+func authenticates(state *configState, username, password string) bool {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(username, password)
+	rec := httptest.NewRecorder()
+	state.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(rec, req)
+	return rec.Code == http.StatusOK
+}
+
+func writeConfig(t *testing.T, path, password string) {
+	t.Helper()
+	content := fmt.Sprintf("basicAuthUsers:\n  alice: %q\n", mustHash(t, password))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+}
+
+func TestConfigStateReloadSwapsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web-config.yml")
+	writeConfig(t, path, "first-password")
+
+	state, err := newConfigState(path)
+	if err != nil {
+		t.Fatalf("newConfigState: %s", err)
+	}
+	if !state.auth.authenticate("alice", "first-password") {
+		t.Fatal("expected the initially loaded config to accept its own password")
+	}
+
+	writeConfig(t, path, "second-password")
+	if err := state.reload(); err != nil {
+		t.Fatalf("reload: %s", err)
+	}
+	if state.auth.authenticate("alice", "first-password") {
+		t.Error("reload should have replaced the old password, but it still authenticates")
+	}
+	if !state.auth.authenticate("alice", "second-password") {
+		t.Error("reload should have picked up the new password")
+	}
+}
+
+func TestConfigStateReloadKeepsPreviousConfigOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web-config.yml")
+	writeConfig(t, path, "first-password")
+
+	state, err := newConfigState(path)
+	if err != nil {
+		t.Fatalf("newConfigState: %s", err)
+	}
+
+	if err := os.WriteFile(path, []byte("not: [valid: yaml"), 0o644); err != nil {
+		t.Fatalf("writing bad config: %s", err)
+	}
+	if err := state.reload(); err == nil {
+		t.Fatal("expected reload to reject an invalid config file")
+	}
+
+	if !state.auth.authenticate("alice", "first-password") {
+		t.Error("a failed reload must leave the previously loaded config in effect")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web-config.yml")
+	writeConfig(t, path, "first-password")
+
+	state, err := newConfigState(path)
+	if err != nil {
+		t.Fatalf("newConfigState: %s", err)
+	}
+
+	registryMtx.Lock()
+	registry[path] = state
+	registryMtx.Unlock()
+	t.Cleanup(func() {
+		registryMtx.Lock()
+		delete(registry, path)
+		registryMtx.Unlock()
+	})
+
+	writeConfig(t, path, "second-password")
+	if err := Reload(path); err != nil {
+		t.Fatalf("Reload: %s", err)
+	}
+	if !state.auth.authenticate("alice", "second-password") {
+		t.Error("Reload should have swapped in the new password")
+	}
+
+	if err := Reload(filepath.Join(t.TempDir(), "never-registered.yml")); err == nil {
+		t.Error("expected Reload to fail for a configPath nothing is listening on")
+	}
+}
+
+func TestWatchConfigReloadsOnFileChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web-config.yml")
+	writeConfig(t, path, "first-password")
+
+	state, err := newConfigState(path)
+	if err != nil {
+		t.Fatalf("newConfigState: %s", err)
+	}
+	watchConfig(state)
+
+	writeConfig(t, path, "second-password")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if authenticates(state, "alice", "second-password") {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("watchConfig did not pick up the file change within the timeout")
+}
@@ -0,0 +1,180 @@
+// Package https provides TLS and HTTP Basic Auth support for the
+// blackbox_exporter's own HTTP endpoints (/probe, /metrics, ...), configured
+// through a small YAML file passed on the command line.
+package https
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config struct holds information to generate a tls.Config and, optionally,
+// protect the server with HTTP Basic Auth.
+type Config struct {
+	TLSCertPath string    `yaml:"tlsCertPath"`
+	TLSKeyPath  string    `yaml:"tlsKeyPath"`
+	TLSConfig   TLSStruct `yaml:"tlsConfig"`
+
+	// BasicAuthUsers maps usernames to bcrypt-hashed passwords. When
+	// non-empty, Listen wraps the server's handler in HTTP Basic Auth.
+	BasicAuthUsers map[string]string `yaml:"basicAuthUsers"`
+}
+
+// TLSStruct forms part of the Config
+type TLSStruct struct {
+	RootCAs                  string        `yaml:"rootCAs"`
+	ServerName               string        `yaml:"serverName"`
+	ClientAuth               string        `yaml:"clientAuth"`
+	ClientCAs                string        `yaml:"clientCAs"`
+	InsecureSkipVerify       bool          `yaml:"insecureSkipVerify"`
+	CipherSuites             []CipherSuite `yaml:"cipherSuites"`
+	PreferServerCipherSuites bool          `yaml:"preferServerCipherSuites"`
+	MinVersion               TLSVersion    `yaml:"minVersion"`
+	MaxVersion               TLSVersion    `yaml:"maxVersion"`
+
+	// UseSystemRoots/UseSystemClientCAs start RootCAs/ClientCAs from the
+	// host OS trust store instead of an empty pool; RootCAs/ClientCAs, if
+	// also set, are appended on top. Setting RootCAs or ClientCAs to the
+	// literal value "system" is equivalent to setting the matching boolean
+	// with no PEM file on top.
+	UseSystemRoots     bool `yaml:"useSystemRoots"`
+	UseSystemClientCAs bool `yaml:"useSystemClientCAs"`
+
+	// Profile pre-populates MinVersion and CipherSuites from the Mozilla
+	// server-side TLS guidelines ("modern", "intermediate" or "old").
+	// Explicit MinVersion/CipherSuites values above still take precedence.
+	Profile string `yaml:"profile"`
+}
+
+// GetTLSConfig takes a path to a yml config file and returns a tls.Config
+// based on its values. Unlike earlier versions of this package, a bad config
+// is returned as an error rather than terminating the process, so that
+// callers driving a reload (see Reload) can keep the previous good config in
+// effect.
+func GetTLSConfig(configPath string) (*tls.Config, error) {
+	cfg, _, _, err := GetConfigAndPaths(configPath)
+	return cfg, err
+}
+
+// GetConfigAndPaths takes a path to a yml config file and returns a
+// tls.Config based on its values, as well as paths to the cert and key
+// files.
+func GetConfigAndPaths(configPath string) (*tls.Config, string, string, error) {
+	config, err := loadConfigFromYaml(configPath)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to load config from %s: %s", configPath, err)
+	}
+	tlsc, err := loadTLSConfig(config)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to build TLS config from %s: %s", configPath, err)
+	}
+	return tlsc, config.TLSCertPath, config.TLSKeyPath, nil
+}
+
+func loadConfigFromYaml(fileName string) (*Config, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	err = yaml.Unmarshal(content, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func loadTLSConfig(c *Config) (*tls.Config, error) {
+	if err := applyProfile(&c.TLSConfig); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{}
+	if len(c.TLSCertPath) > 0 {
+		cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(c.TLSCertPath, c.TLSKeyPath)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		}
+		cfg.BuildNameToCertificate()
+	}
+	if len(c.TLSConfig.ServerName) > 0 {
+		cfg.ServerName = c.TLSConfig.ServerName
+	}
+	if (c.TLSConfig.InsecureSkipVerify) == true {
+		cfg.InsecureSkipVerify = true
+	}
+	if len(c.TLSConfig.CipherSuites) > 0 {
+		cfg.CipherSuites = make([]uint16, len(c.TLSConfig.CipherSuites))
+		for i, suite := range c.TLSConfig.CipherSuites {
+			cfg.CipherSuites[i] = uint16(suite)
+		}
+	}
+	if (c.TLSConfig.PreferServerCipherSuites) == true {
+		cfg.PreferServerCipherSuites = c.TLSConfig.PreferServerCipherSuites
+	}
+	// Default to TLS 1.2 so that upgrading Go does not silently loosen the
+	// minimum accepted version for operators who never set one.
+	if c.TLSConfig.MinVersion == 0 {
+		c.TLSConfig.MinVersion = TLSVersion(tls.VersionTLS12)
+	}
+	cfg.MinVersion = uint16(c.TLSConfig.MinVersion)
+	if (c.TLSConfig.MaxVersion) != 0 {
+		cfg.MaxVersion = uint16(c.TLSConfig.MaxVersion)
+	}
+	useSystemRoots, rootCAs := certPoolRequest(c.TLSConfig.UseSystemRoots, c.TLSConfig.RootCAs)
+	if useSystemRoots || len(rootCAs) > 0 {
+		rootCertPool, err := buildCertPool(useSystemRoots, rootCAs)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.RootCAs = rootCertPool
+	}
+	useSystemClientCAs, clientCAs := certPoolRequest(c.TLSConfig.UseSystemClientCAs, c.TLSConfig.ClientCAs)
+	if useSystemClientCAs || len(clientCAs) > 0 {
+		clientCAPool, err := buildCertPool(useSystemClientCAs, clientCAs)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.ClientCAs = clientCAPool
+	}
+	if len(c.TLSConfig.ClientAuth) > 0 {
+		switch s := (c.TLSConfig.ClientAuth); s {
+		case "RequestClientCert":
+			cfg.ClientAuth = tls.RequestClientCert
+		case "RequireClientCert":
+			cfg.ClientAuth = tls.RequireAnyClientCert
+		case "VerifyClientCertIfGiven":
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		case "RequireAndVerifyClientCert":
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		default:
+			cfg.ClientAuth = tls.NoClientCert
+		}
+	}
+	return cfg, nil
+}
+
+// applyProfile pre-populates MinVersion and CipherSuites from c.Profile,
+// without overriding values the operator already set explicitly.
+func applyProfile(c *TLSStruct) error {
+	if c.Profile == "" {
+		return nil
+	}
+	profile, ok := tlsProfiles[c.Profile]
+	if !ok {
+		return fmt.Errorf("unknown tls profile %q, must be one of modern, intermediate, old", c.Profile)
+	}
+	if c.MinVersion == 0 {
+		c.MinVersion = profile.minVersion
+	}
+	if len(c.CipherSuites) == 0 {
+		c.CipherSuites = profile.cipherSuites
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package https
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLSVersion is a TLS protocol version that unmarshals from the symbolic
+// names used throughout TLS documentation (e.g. "TLS12") rather than the
+// raw uint16 crypto/tls constants, which are easy to get wrong in YAML.
+type TLSVersion uint16
+
+// tlsVersions maps the symbolic names accepted in YAML to their crypto/tls
+// constants.
+var tlsVersions = map[string]uint16{
+	"TLS13": tls.VersionTLS13,
+	"TLS12": tls.VersionTLS12,
+	"TLS11": tls.VersionTLS11,
+	"TLS10": tls.VersionTLS10,
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (v *TLSVersion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	version, ok := tlsVersions[s]
+	if !ok {
+		return fmt.Errorf("unknown TLS version %q, must be one of TLS10, TLS11, TLS12, TLS13", s)
+	}
+	*v = TLSVersion(version)
+	return nil
+}
+
+// CipherSuite is a TLS cipher suite that unmarshals from its crypto/tls
+// symbolic name (e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256") rather than
+// the raw uint16 code.
+type CipherSuite uint16
+
+// cipherSuites maps the symbolic names accepted in YAML to their crypto/tls
+// constants. It is restricted to suites crypto/tls actually implements.
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_FALLBACK_SCSV":                       tls.TLS_FALLBACK_SCSV,
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *CipherSuite) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	suite, ok := cipherSuites[s]
+	if !ok {
+		return fmt.Errorf("unknown cipher suite %q", s)
+	}
+	*c = CipherSuite(suite)
+	return nil
+}
+
+// tlsProfile pre-populates MinVersion and CipherSuites for a named profile.
+// An empty CipherSuites means "let crypto/tls choose", which is correct for
+// TLS 1.3, where the cipher suite is not configurable.
+type tlsProfile struct {
+	minVersion   TLSVersion
+	cipherSuites []CipherSuite
+}
+
+// tlsProfiles implements the "modern", "intermediate" and "old" profiles
+// from the Mozilla server-side TLS guidelines
+// (https://wiki.mozilla.org/Security/Server_Side_TLS).
+var tlsProfiles = map[string]tlsProfile{
+	"modern": {
+		minVersion: TLSVersion(tls.VersionTLS13),
+	},
+	"intermediate": {
+		minVersion: TLSVersion(tls.VersionTLS12),
+		cipherSuites: []CipherSuite{
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256),
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384),
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305),
+		},
+	},
+	"old": {
+		minVersion: TLSVersion(tls.VersionTLS10),
+		cipherSuites: []CipherSuite{
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256),
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384),
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305),
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA),
+			CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA),
+			CipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA),
+			CipherSuite(tls.TLS_RSA_WITH_AES_128_GCM_SHA256),
+			CipherSuite(tls.TLS_RSA_WITH_AES_256_GCM_SHA384),
+			CipherSuite(tls.TLS_RSA_WITH_AES_128_CBC_SHA),
+			CipherSuite(tls.TLS_RSA_WITH_AES_256_CBC_SHA),
+			CipherSuite(tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA),
+		},
+	},
+}
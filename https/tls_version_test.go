@@ -0,0 +1,121 @@
+package https
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestTLSVersionUnmarshalYAML(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{"TLS10", tls.VersionTLS10, false},
+		{"TLS11", tls.VersionTLS11, false},
+		{"TLS12", tls.VersionTLS12, false},
+		{"TLS13", tls.VersionTLS13, false},
+		{"bogus", 0, true},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			var v TLSVersion
+			err := yaml.Unmarshal([]byte(c.in), &v)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error unmarshalling %q, got TLSVersion(%d)", c.in, v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshalling %q: %s", c.in, err)
+			}
+			if uint16(v) != c.want {
+				t.Errorf("unmarshalling %q = %d, want %d", c.in, v, c.want)
+			}
+		})
+	}
+}
+
+func TestCipherSuiteUnmarshalYAML(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, false},
+		{"TLS_RSA_WITH_AES_256_CBC_SHA", tls.TLS_RSA_WITH_AES_256_CBC_SHA, false},
+		{"TLS_NOT_A_REAL_SUITE", 0, true},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			var s CipherSuite
+			err := yaml.Unmarshal([]byte(c.in), &s)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error unmarshalling %q, got CipherSuite(%d)", c.in, s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshalling %q: %s", c.in, err)
+			}
+			if uint16(s) != c.want {
+				t.Errorf("unmarshalling %q = %d, want %d", c.in, s, c.want)
+			}
+		})
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	t.Run("unknown profile is an error", func(t *testing.T) {
+		c := &TLSStruct{Profile: "bogus"}
+		if err := applyProfile(c); err == nil {
+			t.Fatal("expected an error for an unknown profile")
+		}
+	})
+
+	t.Run("profile fills in unset MinVersion and CipherSuites", func(t *testing.T) {
+		c := &TLSStruct{Profile: "intermediate"}
+		if err := applyProfile(c); err != nil {
+			t.Fatalf("applyProfile: %s", err)
+		}
+		want := tlsProfiles["intermediate"]
+		if c.MinVersion != want.minVersion {
+			t.Errorf("MinVersion = %d, want %d", c.MinVersion, want.minVersion)
+		}
+		if len(c.CipherSuites) != len(want.cipherSuites) {
+			t.Errorf("CipherSuites = %v, want %v", c.CipherSuites, want.cipherSuites)
+		}
+	})
+
+	t.Run("explicit MinVersion and CipherSuites take precedence over the profile", func(t *testing.T) {
+		explicitSuites := []CipherSuite{CipherSuite(tls.TLS_RSA_WITH_AES_128_CBC_SHA)}
+		c := &TLSStruct{
+			Profile:      "modern",
+			MinVersion:   TLSVersion(tls.VersionTLS10),
+			CipherSuites: explicitSuites,
+		}
+		if err := applyProfile(c); err != nil {
+			t.Fatalf("applyProfile: %s", err)
+		}
+		if c.MinVersion != TLSVersion(tls.VersionTLS10) {
+			t.Errorf("MinVersion = %d, want explicit TLS10 to survive, got overridden by profile", c.MinVersion)
+		}
+		if len(c.CipherSuites) != 1 || c.CipherSuites[0] != explicitSuites[0] {
+			t.Errorf("CipherSuites = %v, want explicit value to survive, got overridden by profile", c.CipherSuites)
+		}
+	})
+
+	t.Run("no profile is a no-op", func(t *testing.T) {
+		c := &TLSStruct{}
+		if err := applyProfile(c); err != nil {
+			t.Fatalf("applyProfile: %s", err)
+		}
+		if c.MinVersion != 0 || len(c.CipherSuites) != 0 {
+			t.Errorf("expected no changes with no profile set, got MinVersion=%d CipherSuites=%v", c.MinVersion, c.CipherSuites)
+		}
+	})
+}